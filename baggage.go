@@ -0,0 +1,299 @@
+package tracecontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	otelbaggage "go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	// BaggageHTTPHeaderTag is the HTTP header tag for baggage.
+	BaggageHTTPHeaderTag = "baggage"
+
+	// baggageMaxMembers is the maximum number of members in a baggage header.
+	baggageMaxMembers = 180
+
+	// baggageMaxLength is the maximum length of a baggage header.
+	baggageMaxLength = 8192
+)
+
+var (
+	// errBaggageInvalidFormat is returned when the baggage format is invalid.
+	errBaggageInvalidFormat = errors.New("invalid baggage format")
+	// errBaggageInvalidKey is returned when a baggage key is not a valid token.
+	errBaggageInvalidKey = errors.New("invalid baggage key")
+	// errBaggageTooLarge is returned when the baggage exceeds the member or length limits.
+	errBaggageTooLarge = errors.New("baggage too large")
+)
+
+// Baggage is an ordered list of W3C baggage members.
+type Baggage struct {
+	members []baggageMember
+}
+
+// baggageMember is a single baggage list member.
+// Properties are kept in their received form.
+type baggageMember struct {
+	key        string
+	value      string
+	properties []string
+}
+
+// baggageContextKey is the context key for Baggage.
+type baggageContextKey struct{}
+
+// ContextWithBaggage returns a copy of ctx holding the baggage.
+func ContextWithBaggage(ctx context.Context, b Baggage) context.Context {
+	return context.WithValue(ctx, baggageContextKey{}, b)
+}
+
+// BaggageFromContext returns the baggage held by ctx, or empty baggage if there is none.
+func BaggageFromContext(ctx context.Context) Baggage {
+	b, _ := ctx.Value(baggageContextKey{}).(Baggage)
+
+	return b
+}
+
+// ParseBaggage parses a W3C baggage header value.
+func ParseBaggage(baggage string) (Baggage, error) {
+	if len(baggage) > baggageMaxLength {
+		return Baggage{}, fmt.Errorf("%w: length %d", errBaggageTooLarge, len(baggage))
+	}
+
+	var b Baggage
+
+	for _, str := range strings.Split(baggage, ",") {
+		if str = trimOWS(str); str == "" {
+			continue
+		}
+
+		m, err := parseBaggageMember(str)
+		if err != nil {
+			return Baggage{}, err
+		}
+
+		b.members = append(b.members, m)
+
+		if len(b.members) > baggageMaxMembers {
+			return Baggage{}, fmt.Errorf("%w: more than %d members", errBaggageTooLarge, baggageMaxMembers)
+		}
+	}
+
+	return b, nil
+}
+
+// Set sets the value of key, replacing an existing member or appending a new one.
+// It fails if the serialized baggage would exceed the member or length limits.
+// The members are copied before they are changed, so other copies of the Baggage,
+// such as one stored in a context, are left untouched.
+func (b *Baggage) Set(key, value string) error {
+	if !isBaggageToken(key) {
+		return fmt.Errorf("%w: %s", errBaggageInvalidKey, key)
+	}
+
+	members := slices.Clone(b.members)
+
+	if i := slices.IndexFunc(members, func(m baggageMember) bool { return m.key == key }); i >= 0 {
+		members[i] = baggageMember{key: key, value: value}
+	} else {
+		if len(members) == baggageMaxMembers {
+			return fmt.Errorf("%w: more than %d members", errBaggageTooLarge, baggageMaxMembers)
+		}
+
+		members = append(members, baggageMember{key: key, value: value})
+	}
+
+	if n := len(Baggage{members: members}.Serialize()); n > baggageMaxLength {
+		return fmt.Errorf("%w: length %d", errBaggageTooLarge, n)
+	}
+
+	b.members = members
+
+	return nil
+}
+
+// withOTelBaggage returns a copy of b that also holds the members of the OpenTelemetry baggage ob
+// whose keys b does not hold, in key order. Members that would exceed the member or length limits
+// are left out.
+func (b Baggage) withOTelBaggage(ob otelbaggage.Baggage) Baggage {
+	otelMembers := ob.Members()
+	if len(otelMembers) == 0 {
+		return b
+	}
+
+	slices.SortFunc(otelMembers, func(x, y otelbaggage.Member) int { return strings.Compare(x.Key(), y.Key()) })
+
+	merged := Baggage{members: slices.Clone(b.members)}
+	length := len(merged.Serialize())
+
+	for _, om := range otelMembers {
+		if !isBaggageToken(om.Key()) || len(merged.members) == baggageMaxMembers {
+			continue
+		}
+
+		if _, ok := merged.Get(om.Key()); ok {
+			continue
+		}
+
+		m := baggageMember{key: om.Key(), value: om.Value()}
+		for _, p := range om.Properties() {
+			m.properties = append(m.properties, p.String())
+		}
+
+		n := len(Baggage{members: []baggageMember{m}}.Serialize())
+		if len(merged.members) > 0 {
+			n++
+		}
+
+		if length+n > baggageMaxLength {
+			continue
+		}
+
+		merged.members = append(merged.members, m)
+		length += n
+	}
+
+	return merged
+}
+
+// Get returns the decoded value of key.
+func (b Baggage) Get(key string) (string, bool) {
+	for _, m := range b.members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+
+	return "", false
+}
+
+// Serialize returns the baggage header value, percent-encoding member values.
+func (b Baggage) Serialize() string {
+	var sb strings.Builder
+
+	for i, m := range b.members {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		sb.WriteString(m.key)
+		sb.WriteByte('=')
+		writeBaggageValue(&sb, m.value)
+
+		for _, p := range m.properties {
+			sb.WriteByte(';')
+			sb.WriteString(p)
+		}
+	}
+
+	return sb.String()
+}
+
+// parseBaggageMember parses a single key=value member with optional properties.
+func parseBaggageMember(str string) (baggageMember, error) {
+	parts := strings.Split(str, ";")
+
+	key, value, ok := strings.Cut(parts[0], "=")
+	if !ok {
+		return baggageMember{}, fmt.Errorf("%w: %s", errBaggageInvalidFormat, str)
+	}
+
+	if key = trimOWS(key); !isBaggageToken(key) {
+		return baggageMember{}, fmt.Errorf("%w: %s", errBaggageInvalidKey, key)
+	}
+
+	decoded, err := decodeBaggageValue(trimOWS(value))
+	if err != nil {
+		return baggageMember{}, err
+	}
+
+	m := baggageMember{key: key, value: decoded}
+
+	for _, p := range parts[1:] {
+		p = trimOWS(p)
+
+		pkey, pvalue, ok := strings.Cut(p, "=")
+		if !isBaggageToken(trimOWS(pkey)) {
+			return baggageMember{}, fmt.Errorf("%w: %s", errBaggageInvalidKey, pkey)
+		}
+
+		if ok {
+			pvalue = trimOWS(pvalue)
+			if _, err := decodeBaggageValue(pvalue); err != nil {
+				return baggageMember{}, err
+			}
+
+			p = trimOWS(pkey) + "=" + pvalue
+		}
+
+		m.properties = append(m.properties, p)
+	}
+
+	return m, nil
+}
+
+// decodeBaggageValue checks a raw member or property value against the baggage value grammar
+// and percent-decodes it.
+func decodeBaggageValue(value string) (string, error) {
+	for i := range len(value) {
+		if !isBaggageOctet(value[i]) && value[i] != '%' {
+			return "", fmt.Errorf("%w: %s", errBaggageInvalidFormat, value)
+		}
+	}
+
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errBaggageInvalidFormat, err)
+	}
+
+	return decoded, nil
+}
+
+// writeBaggageValue writes value, percent-encoding bytes outside the baggage-octet range.
+func writeBaggageValue(sb *strings.Builder, value string) {
+	const hexDigits = "0123456789ABCDEF"
+
+	for i := range len(value) {
+		if c := value[i]; isBaggageOctet(c) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+}
+
+// isBaggageOctet reports whether c may appear unencoded in a baggage value.
+// The percent sign is excluded so that it is always encoded.
+func isBaggageOctet(c byte) bool {
+	return c == 0x21 || (c >= 0x23 && c <= 0x2b && c != '%') || (c >= 0x2d && c <= 0x3a) ||
+		(c >= 0x3c && c <= 0x5b) || (c >= 0x5d && c <= 0x7e)
+}
+
+// isBaggageToken reports whether str is a non-empty RFC 7230 token.
+func isBaggageToken(str string) bool {
+	if str == "" {
+		return false
+	}
+
+	for i := range len(str) {
+		c := str[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&'*+-.^_`|~%", c) >= 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trimOWS trims optional whitespace (spaces and tabs) from both ends of str.
+func trimOWS(str string) string {
+	return strings.Trim(str, " \t")
+}
@@ -0,0 +1,127 @@
+package tracecontext
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseBaggage(t *testing.T) {
+	b, err := ParseBaggage("userId=alice, serverNode = DF%2028;prop1;p2=v ,isProduction=false")
+	if err != nil {
+		t.Fatalf("ParseBaggage() error = %v", err)
+	}
+
+	for key, want := range map[string]string{"userId": "alice", "serverNode": "DF 28", "isProduction": "false"} {
+		if got, ok := b.Get(key); !ok || got != want {
+			t.Fatalf("Get(%q) = %q, %v, want %q, true", key, got, ok, want)
+		}
+	}
+
+	if _, ok := b.Get("missing"); ok {
+		t.Fatal("Get(\"missing\") reported a value")
+	}
+
+	const want = "userId=alice,serverNode=DF%2028;prop1;p2=v,isProduction=false"
+	if got := b.Serialize(); got != want {
+		t.Fatalf("Serialize() = %q, want %q", got, want)
+	}
+}
+
+func TestBaggagePercentEncodingRoundTrip(t *testing.T) {
+	const value = "hello, world; 100%"
+
+	var b Baggage
+
+	if err := b.Set("msg", value); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	parsed, err := ParseBaggage(b.Serialize())
+	if err != nil {
+		t.Fatalf("ParseBaggage(%q) error = %v", b.Serialize(), err)
+	}
+
+	if got, _ := parsed.Get("msg"); got != value {
+		t.Fatalf("round-tripped value = %q, want %q", got, value)
+	}
+}
+
+func TestBaggageSetDoesNotAlias(t *testing.T) {
+	b, err := ParseBaggage("k1=v1,k2=v2")
+	if err != nil {
+		t.Fatalf("ParseBaggage() error = %v", err)
+	}
+
+	ctx := ContextWithBaggage(context.Background(), b)
+
+	c := BaggageFromContext(ctx)
+	if err := c.Set("k1", "CHANGED"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if got, _ := BaggageFromContext(ctx).Get("k1"); got != "v1" {
+		t.Fatalf("baggage in context changed to k1=%q", got)
+	}
+
+	// Appending to two copies must not let one overwrite the other.
+	c1, c2 := b, b
+	_ = c1.Set("k3", "one")
+	_ = c2.Set("k3", "two")
+
+	if got, _ := c1.Get("k3"); got != "one" {
+		t.Fatalf("c1 k3 = %q, want %q", got, "one")
+	}
+}
+
+func TestBaggageSetLengthLimit(t *testing.T) {
+	var (
+		b   Baggage
+		err error
+	)
+
+	value := strings.Repeat("v", 100)
+
+	for i := 0; err == nil; i++ {
+		err = b.Set("key"+strconv.Itoa(i), value)
+	}
+
+	if !errors.Is(err, errBaggageTooLarge) {
+		t.Fatalf("Set() error = %v, want %v", err, errBaggageTooLarge)
+	}
+
+	serialized := b.Serialize()
+	if len(serialized) > baggageMaxLength {
+		t.Fatalf("Serialize() length = %d, want at most %d", len(serialized), baggageMaxLength)
+	}
+
+	if _, err := ParseBaggage(serialized); err != nil {
+		t.Fatalf("ParseBaggage(Serialize()) error = %v", err)
+	}
+}
+
+func TestParseBaggageInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		baggage string
+		wantErr error
+	}{
+		{"missing equals", "k", errBaggageInvalidFormat},
+		{"invalid key", "k k=v", errBaggageInvalidKey},
+		{"whitespace in value", "k=a b", errBaggageInvalidFormat},
+		{"invalid escape", "k=%zz", errBaggageInvalidFormat},
+		{"whitespace in property value", "k=v;p=a b", errBaggageInvalidFormat},
+		{"invalid escape in property value", "k=v;p=%zz", errBaggageInvalidFormat},
+		{"invalid property key", "k=v;p p=v", errBaggageInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBaggage(tt.baggage); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseBaggage(%q) error = %v, want %v", tt.baggage, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package tracecontext
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Decoder decodes hex trace IDs, caching the most recently used ones.
+// It is useful when the same trace IDs are seen many times, e.g. on fan-out.
+// A Decoder is safe for concurrent use.
+type Decoder struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// decoderEntry is a cached trace ID. The order list keeps entries from most to least recently used.
+type decoderEntry struct {
+	key string
+	id  trace.TraceID
+}
+
+// NewDecoder returns a Decoder caching up to capacity trace IDs.
+// A capacity below one disables caching.
+func NewDecoder(capacity int) *Decoder {
+	return &Decoder{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// DecodeTraceID decodes a 32-character lowercase hex trace ID.
+// All-zero trace IDs are rejected with ErrInvalidTraceID.
+func (d *Decoder) DecodeTraceID(traceID string) (trace.TraceID, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.entries[traceID]; ok {
+		d.order.MoveToFront(e)
+
+		return e.Value.(*decoderEntry).id, nil
+	}
+
+	id, err := decodeTraceID(traceID)
+	if err != nil {
+		return trace.TraceID{}, err
+	}
+
+	if d.capacity < 1 {
+		return id, nil
+	}
+
+	// Clone the key so that the cache does not keep a larger string, such as a whole header, alive.
+	key := strings.Clone(traceID)
+	d.entries[key] = d.order.PushFront(&decoderEntry{key: key, id: id})
+
+	if d.order.Len() > d.capacity {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(*decoderEntry).key)
+	}
+
+	return id, nil
+}
+
+// Len returns the number of cached trace IDs.
+func (d *Decoder) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.order.Len()
+}
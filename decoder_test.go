@@ -0,0 +1,115 @@
+package tracecontext
+
+import (
+	"errors"
+	"testing"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestDecoderCacheHit(t *testing.T) {
+	d := NewDecoder(2)
+
+	want, err := d.DecodeTraceID(testTraceID)
+	if err != nil {
+		t.Fatalf("DecodeTraceID(%q) error = %v", testTraceID, err)
+	}
+
+	got, err := d.DecodeTraceID(testTraceID)
+	if err != nil {
+		t.Fatalf("DecodeTraceID(%q) error = %v", testTraceID, err)
+	}
+
+	if got != want {
+		t.Fatalf("DecodeTraceID(%q) = %v, want %v", testTraceID, got, want)
+	}
+
+	if got.String() != testTraceID {
+		t.Fatalf("DecodeTraceID(%q) = %v", testTraceID, got)
+	}
+
+	if n := d.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+}
+
+func TestDecoderEviction(t *testing.T) {
+	const (
+		first  = "00000000000000000000000000000001"
+		second = "00000000000000000000000000000002"
+		third  = "00000000000000000000000000000003"
+	)
+
+	d := NewDecoder(2)
+
+	for _, traceID := range []string{first, second, first, third} {
+		if _, err := d.DecodeTraceID(traceID); err != nil {
+			t.Fatalf("DecodeTraceID(%q) error = %v", traceID, err)
+		}
+	}
+
+	if n := d.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+
+	// first was used more recently than second, so second is the one evicted.
+	for traceID, want := range map[string]bool{first: true, second: false, third: true} {
+		if _, ok := d.entries[traceID]; ok != want {
+			t.Fatalf("%q cached = %v, want %v", traceID, ok, want)
+		}
+	}
+}
+
+func TestDecoderNoCache(t *testing.T) {
+	d := NewDecoder(0)
+
+	if _, err := d.DecodeTraceID(testTraceID); err != nil {
+		t.Fatalf("DecodeTraceID(%q) error = %v", testTraceID, err)
+	}
+
+	if n := d.Len(); n != 0 {
+		t.Fatalf("Len() = %d, want 0", n)
+	}
+}
+
+func TestDecoderInvalid(t *testing.T) {
+	d := NewDecoder(2)
+
+	for _, traceID := range []string{"00000000000000000000000000000000", "4BF92F3577B34DA6A3CE929D0E0E4736", "4bf92f"} {
+		if _, err := d.DecodeTraceID(traceID); err == nil {
+			t.Fatalf("DecodeTraceID(%q) error = nil, want an error", traceID)
+		}
+	}
+
+	if _, err := d.DecodeTraceID("00000000000000000000000000000000"); !errors.Is(err, ErrInvalidTraceID) {
+		t.Fatalf("DecodeTraceID() error = %v, want %v", err, ErrInvalidTraceID)
+	}
+
+	if n := d.Len(); n != 0 {
+		t.Fatalf("Len() = %d, want 0", n)
+	}
+}
+
+func BenchmarkDecoderCached(b *testing.B) {
+	d := NewDecoder(16)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := d.DecodeTraceID(testTraceID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoderUncached(b *testing.B) {
+	d := NewDecoder(0)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := d.DecodeTraceID(testTraceID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
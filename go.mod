@@ -2,6 +2,7 @@ module github.com/amsokol/tracecontext
 
 go 1.23.1
 
-require go.opentelemetry.io/otel/trace v1.30.0
-
-require go.opentelemetry.io/otel v1.30.0 // indirect
+require (
+	go.opentelemetry.io/otel v1.30.0
+	go.opentelemetry.io/otel/trace v1.30.0
+)
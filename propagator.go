@@ -0,0 +1,152 @@
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	otelbaggage "go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Propagator propagates span contexts using the W3C traceparent and tracestate headers.
+// The zero value is ready to use; NewPropagator enables optional behavior.
+type Propagator struct {
+	traceparentKey string
+	tracestateKey  string
+	baggage        bool
+}
+
+// PropagatorOption configures a Propagator.
+type PropagatorOption func(*Propagator)
+
+var _ propagation.TextMapPropagator = Propagator{}
+
+// NewPropagator returns a Propagator configured by the given options.
+func NewPropagator(opts ...PropagatorOption) Propagator {
+	var p Propagator
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// WithBaggage makes the Propagator also inject and extract the W3C baggage header.
+// Injected baggage combines the Baggage from BaggageFromContext with the OpenTelemetry baggage
+// from go.opentelemetry.io/otel/baggage, the former winning for keys held by both.
+// Extracted baggage is stored in both, so OpenTelemetry instrumentation sees it as well.
+func WithBaggage() PropagatorOption {
+	return func(p *Propagator) {
+		p.baggage = true
+	}
+}
+
+// WithHeaderKeys overrides the carrier keys used for traceparent and tracestate,
+// e.g. for gateways that rename the standard headers. An empty key keeps the default.
+func WithHeaderKeys(traceparent, tracestate string) PropagatorOption {
+	return func(p *Propagator) {
+		p.traceparentKey = traceparent
+		p.tracestateKey = tracestate
+	}
+}
+
+// Inject writes the span context from ctx into the carrier.
+func (p Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if p.baggage {
+		if baggage := BaggageFromContext(ctx).withOTelBaggage(otelbaggage.FromContext(ctx)).Serialize(); baggage != "" {
+			carrier.Set(BaggageHTTPHeaderTag, baggage)
+		}
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	traceparentKey, tracestateKey := p.keys()
+
+	carrier.Set(traceparentKey, Marshal(sc))
+
+	if tracestate := sc.TraceState().String(); tracestate != "" {
+		carrier.Set(tracestateKey, tracestate)
+	}
+}
+
+// Extract reads a remote span context from the carrier and stores it in the returned context.
+// The original context is returned if the carrier holds no valid traceparent.
+func (p Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if p.baggage {
+		header := carrier.Get(BaggageHTTPHeaderTag)
+
+		if baggage, err := ParseBaggage(header); err == nil && len(baggage.members) > 0 {
+			ctx = ContextWithBaggage(ctx, baggage)
+		}
+
+		if baggage, err := otelbaggage.Parse(header); err == nil && baggage.Len() > 0 {
+			ctx = otelbaggage.ContextWithBaggage(ctx, baggage)
+		}
+	}
+
+	traceparentKey, tracestateKey := p.keys()
+
+	cfg, err := Unmarshal(carrier.Get(traceparentKey), carrier.Get(tracestateKey))
+	if err != nil {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(cfg))
+}
+
+// Fields returns the carrier keys used by the propagator.
+func (p Propagator) Fields() []string {
+	traceparentKey, tracestateKey := p.keys()
+
+	if p.baggage {
+		return []string{traceparentKey, tracestateKey, BaggageHTTPHeaderTag}
+	}
+
+	return []string{traceparentKey, tracestateKey}
+}
+
+// keys returns the configured traceparent and tracestate carrier keys, falling back to the defaults.
+func (p Propagator) keys() (string, string) {
+	traceparentKey, tracestateKey := TraceparentHTTPHeaderTag, TracestateHTTPHeaderTag
+
+	if p.traceparentKey != "" {
+		traceparentKey = p.traceparentKey
+	}
+
+	if p.tracestateKey != "" {
+		tracestateKey = p.tracestateKey
+	}
+
+	return traceparentKey, tracestateKey
+}
+
+// ContextFromHeader returns a copy of ctx holding the remote span context parsed from the HTTP header.
+// Multiple header lines are combined, so several traceparent lines are rejected like comma-joined values
+// and a tracestate split across lines keeps all its members.
+// The original context is returned if the header holds no valid traceparent.
+func ContextFromHeader(ctx context.Context, h http.Header) context.Context {
+	cfg, err := Unmarshal(strings.Join(h.Values(TraceparentHTTPHeaderTag), ","),
+		strings.Join(h.Values(TracestateHTTPHeaderTag), ","))
+	if err != nil {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(cfg))
+}
+
+// InjectIntoContext parses the traceparent and tracestate header values and returns a copy of ctx
+// holding the resulting remote span context. On failure the original context is returned with the error.
+func InjectIntoContext(ctx context.Context, traceparent, tracestate string) (context.Context, error) {
+	cfg, err := Unmarshal(traceparent, tracestate)
+	if err != nil {
+		return ctx, err
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(cfg)), nil
+}
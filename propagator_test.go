@@ -0,0 +1,278 @@
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	otelbaggage "go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTracestate = "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"
+
+// testContext returns a context holding the span context of testTraceparent and testTracestate.
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	cfg, err := Unmarshal(testTraceparent, testTracestate)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(cfg))
+}
+
+func TestPropagatorRoundTrip(t *testing.T) {
+	ctx := testContext(t)
+	carrier := propagation.MapCarrier{}
+
+	Propagator{}.Inject(ctx, carrier)
+
+	if got := carrier.Get(TraceparentHTTPHeaderTag); got != testTraceparent {
+		t.Fatalf("injected traceparent = %q, want %q", got, testTraceparent)
+	}
+
+	if got := carrier.Get(TracestateHTTPHeaderTag); got != testTracestate {
+		t.Fatalf("injected tracestate = %q, want %q", got, testTracestate)
+	}
+
+	got := trace.SpanContextFromContext(Propagator{}.Extract(context.Background(), carrier))
+	want := trace.SpanContextFromContext(ctx).WithRemote(true)
+
+	if !got.Equal(want) {
+		t.Fatalf("extracted span context = %v, want %v", got, want)
+	}
+}
+
+func TestPropagatorFields(t *testing.T) {
+	want := []string{TraceparentHTTPHeaderTag, TracestateHTTPHeaderTag}
+
+	if got := (Propagator{}).Fields(); !slices.Equal(got, want) {
+		t.Fatalf("Fields() = %v, want %v", got, want)
+	}
+}
+
+func TestContextFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceparentHTTPHeaderTag, testTraceparent)
+
+	sc := trace.SpanContextFromContext(ContextFromHeader(context.Background(), h))
+	if !sc.IsValid() || !sc.IsRemote() {
+		t.Fatalf("span context = %v, want a valid remote span context", sc)
+	}
+
+	if got := Marshal(sc); got != testTraceparent {
+		t.Fatalf("span context traceparent = %q, want %q", got, testTraceparent)
+	}
+
+	h.Set(TraceparentHTTPHeaderTag, "invalid")
+
+	ctx := context.Background()
+	if got := ContextFromHeader(ctx, h); got != ctx {
+		t.Fatal("ContextFromHeader changed the context for an invalid header")
+	}
+}
+
+func TestPropagatorBaggage(t *testing.T) {
+	var b Baggage
+	if err := b.Set("userId", "alice"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	ctx := ContextWithBaggage(testContext(t), b)
+
+	t.Run("enabled", func(t *testing.T) {
+		p := NewPropagator(WithBaggage())
+		carrier := propagation.MapCarrier{}
+
+		p.Inject(ctx, carrier)
+
+		if got := carrier.Get(BaggageHTTPHeaderTag); got != "userId=alice" {
+			t.Fatalf("injected baggage = %q, want %q", got, "userId=alice")
+		}
+
+		got, ok := BaggageFromContext(p.Extract(context.Background(), carrier)).Get("userId")
+		if !ok || got != "alice" {
+			t.Fatalf("extracted baggage userId = %q, %v, want %q, true", got, ok, "alice")
+		}
+
+		want := []string{TraceparentHTTPHeaderTag, TracestateHTTPHeaderTag, BaggageHTTPHeaderTag}
+		if fields := p.Fields(); !slices.Equal(fields, want) {
+			t.Fatalf("Fields() = %v, want %v", fields, want)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p := NewPropagator()
+		carrier := propagation.MapCarrier{}
+
+		p.Inject(ctx, carrier)
+
+		if got := carrier.Get(BaggageHTTPHeaderTag); got != "" {
+			t.Fatalf("injected baggage = %q, want none", got)
+		}
+
+		carrier.Set(BaggageHTTPHeaderTag, "userId=alice")
+
+		if _, ok := BaggageFromContext(p.Extract(context.Background(), carrier)).Get("userId"); ok {
+			t.Fatal("Extract() stored baggage with WithBaggage disabled")
+		}
+	})
+}
+
+func TestInjectIntoContext(t *testing.T) {
+	ctx, err := InjectIntoContext(context.Background(), testTraceparent, testTracestate)
+	if err != nil {
+		t.Fatalf("InjectIntoContext() error = %v", err)
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsRemote() {
+		t.Fatalf("span context = %v, want a valid remote span context", sc)
+	}
+
+	if got := Marshal(sc); got != testTraceparent {
+		t.Fatalf("span context traceparent = %q, want %q", got, testTraceparent)
+	}
+
+	if got := sc.TraceState().String(); got != testTracestate {
+		t.Fatalf("span context tracestate = %q, want %q", got, testTracestate)
+	}
+
+	ctx = context.Background()
+
+	got, err := InjectIntoContext(ctx, "invalid", "")
+	if err == nil {
+		t.Fatal("InjectIntoContext() error = nil, want an error")
+	}
+
+	if got != ctx {
+		t.Fatal("InjectIntoContext changed the context for an invalid traceparent")
+	}
+}
+
+func TestPropagatorHeaderKeys(t *testing.T) {
+	ctx := testContext(t)
+
+	t.Run("custom", func(t *testing.T) {
+		p := NewPropagator(WithHeaderKeys("x-traceparent", "x-tracestate"))
+		carrier := propagation.MapCarrier{}
+
+		p.Inject(ctx, carrier)
+
+		if got := carrier.Get("x-traceparent"); got != testTraceparent {
+			t.Fatalf("injected x-traceparent = %q, want %q", got, testTraceparent)
+		}
+
+		if got := carrier.Get("x-tracestate"); got != testTracestate {
+			t.Fatalf("injected x-tracestate = %q, want %q", got, testTracestate)
+		}
+
+		if keys := carrier.Keys(); len(keys) != 2 {
+			t.Fatalf("carrier keys = %v, want only the custom keys", keys)
+		}
+
+		sc := trace.SpanContextFromContext(p.Extract(context.Background(), carrier))
+		if got := Marshal(sc); got != testTraceparent {
+			t.Fatalf("extracted traceparent = %q, want %q", got, testTraceparent)
+		}
+
+		want := []string{"x-traceparent", "x-tracestate"}
+		if fields := p.Fields(); !slices.Equal(fields, want) {
+			t.Fatalf("Fields() = %v, want %v", fields, want)
+		}
+	})
+
+	for name, p := range map[string]Propagator{
+		"unconfigured": NewPropagator(),
+		"empty keys":   NewPropagator(WithHeaderKeys("", "")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			carrier := propagation.MapCarrier{}
+
+			p.Inject(ctx, carrier)
+
+			if got := carrier.Get(TraceparentHTTPHeaderTag); got != testTraceparent {
+				t.Fatalf("injected traceparent = %q, want %q", got, testTraceparent)
+			}
+
+			want := []string{TraceparentHTTPHeaderTag, TracestateHTTPHeaderTag}
+			if fields := p.Fields(); !slices.Equal(fields, want) {
+				t.Fatalf("Fields() = %v, want %v", fields, want)
+			}
+		})
+	}
+}
+
+func TestContextFromHeaderMultipleLines(t *testing.T) {
+	t.Run("duplicated traceparent", func(t *testing.T) {
+		h := http.Header{}
+		h.Add(TraceparentHTTPHeaderTag, testTraceparent)
+		h.Add(TraceparentHTTPHeaderTag, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		ctx := context.Background()
+		if got := ContextFromHeader(ctx, h); got != ctx {
+			t.Fatal("ContextFromHeader changed the context for a duplicated traceparent")
+		}
+	})
+
+	t.Run("tracestate split across lines", func(t *testing.T) {
+		h := http.Header{}
+		h.Set(TraceparentHTTPHeaderTag, testTraceparent)
+		h.Add(TracestateHTTPHeaderTag, "rojo=00f067aa0ba902b7")
+		h.Add(TracestateHTTPHeaderTag, "congo=t61rcWkgMzE")
+
+		sc := trace.SpanContextFromContext(ContextFromHeader(context.Background(), h))
+		if got := sc.TraceState().String(); got != testTracestate {
+			t.Fatalf("span context tracestate = %q, want %q", got, testTracestate)
+		}
+	})
+}
+
+func TestPropagatorOTelBaggage(t *testing.T) {
+	p := NewPropagator(WithBaggage())
+
+	member := func(key, value string) otelbaggage.Member {
+		t.Helper()
+
+		m, err := otelbaggage.NewMember(key, value)
+		if err != nil {
+			t.Fatalf("NewMember(%q, %q) error = %v", key, value, err)
+		}
+
+		return m
+	}
+
+	t.Run("inject", func(t *testing.T) {
+		ob, err := otelbaggage.New(member("userId", "bob"), member("tenant", "acme"))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		var b Baggage
+		if err := b.Set("userId", "alice"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+
+		ctx := ContextWithBaggage(otelbaggage.ContextWithBaggage(context.Background(), ob), b)
+		carrier := propagation.MapCarrier{}
+
+		p.Inject(ctx, carrier)
+
+		if got, want := carrier.Get(BaggageHTTPHeaderTag), "userId=alice,tenant=acme"; got != want {
+			t.Fatalf("injected baggage = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		carrier := propagation.MapCarrier{BaggageHTTPHeaderTag: "userId=alice;p=1"}
+
+		ob := otelbaggage.FromContext(p.Extract(context.Background(), carrier))
+		if got := ob.Member("userId").Value(); got != "alice" {
+			t.Fatalf("extracted OpenTelemetry baggage userId = %q, want %q", got, "alice")
+		}
+	})
+}
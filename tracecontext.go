@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -12,68 +13,456 @@ const (
 	// TraceparentVersion is the version of the traceparent header.
 	traceparentVersion = "00"
 
+	// traceparentReservedVersion is the traceparent version reserved as invalid.
+	traceparentReservedVersion = "ff"
+
 	// TraceparentHTTPHeaderTag is the HTTP header tag for traceparent.
 	TraceparentHTTPHeaderTag = "traceparent"
 
 	// TraceparentHTTPHeaderTag is the HTTP header tag for traceparent.
 	TracestateHTTPHeaderTag = "tracestate"
 
-	// traceparentParts is the number of parts in a traceparent header.
-	traceparentParts = 4
+	// traceparentLength is the length of a version 00 traceparent header.
+	traceparentLength = 55
+
+	// traceparentMaxLength is the maximum accepted length of a traceparent header, including
+	// optional whitespace. It leaves room for the additional fields of higher versions while
+	// rejecting oversized headers before they are scanned.
+	traceparentMaxLength = 512
+
+	// tracestateMaxLength is the maximum accepted length of a tracestate header. It is well above
+	// the 512 characters the spec asks vendors to propagate.
+	tracestateMaxLength = 8192
+
+	// traceIDOffset is the offset of the trace ID in a traceparent header.
+	traceIDOffset = 3
+
+	// spanIDOffset is the offset of the parent ID in a traceparent header.
+	spanIDOffset = 36
+
+	// flagsOffset is the offset of the flags in a traceparent header.
+	flagsOffset = 53
 )
 
 var (
-	// errTraceparentInvalidFormat is returned when the traceparent format is invalid.
-	errTraceparentInvalidFormat = errors.New("invalid traceparent format")
-	// errTraceparentInvalidVersion is returned when the traceparent version is invalid.
-	errTraceparentInvalidVersion = errors.New("invalid traceparent version")
+	// ErrInvalidFormat is returned when the traceparent format is invalid.
+	ErrInvalidFormat = errors.New("invalid traceparent format")
+	// ErrInvalidVersion is returned when the traceparent version is invalid.
+	ErrInvalidVersion = errors.New("invalid traceparent version")
+	// ErrVersionReserved is returned for the reserved traceparent version ff. It wraps ErrInvalidVersion.
+	ErrVersionReserved = fmt.Errorf("%w: reserved", ErrInvalidVersion)
+	// ErrVersionUnsupported is returned for traceparent versions that cannot be parsed,
+	// i.e. that are not two lowercase hex characters. It wraps ErrInvalidVersion.
+	ErrVersionUnsupported = fmt.Errorf("%w: unsupported", ErrInvalidVersion)
+	// ErrInvalidTraceID is returned when the traceparent trace ID is all zeros.
+	ErrInvalidTraceID = errors.New("invalid trace ID")
+	// ErrInvalidSpanID is returned when the traceparent parent ID is all zeros.
+	ErrInvalidSpanID = errors.New("invalid span ID")
+	// ErrMultipleTraceparent is returned when the traceparent holds several comma-joined values.
+	ErrMultipleTraceparent = errors.New("multiple traceparent values")
+	// ErrInvalidTracestate is returned when the tracestate is too long or cannot be parsed.
+	ErrInvalidTracestate = errors.New("invalid tracestate")
 )
 
-func Marshal(sc trace.SpanContext) string {
-	return fmt.Sprintf("%s-%s-%s-%s",
-		traceparentVersion, sc.TraceID().String(), sc.SpanID().String(), sc.TraceFlags().String())
+// Fields reported by ParseError.
+const (
+	// FieldTraceparent is the traceparent header as a whole, e.g. when its layout is invalid.
+	FieldTraceparent = "traceparent"
+	// FieldVersion is the traceparent version.
+	FieldVersion = "version"
+	// FieldTraceID is the traceparent trace ID.
+	FieldTraceID = "trace-id"
+	// FieldParentID is the traceparent parent ID.
+	FieldParentID = "parent-id"
+	// FieldTraceFlags is the traceparent trace flags.
+	FieldTraceFlags = "trace-flags"
+	// FieldTracestate is the tracestate header.
+	FieldTracestate = "tracestate"
+)
+
+// ParseError is returned when a traceparent or tracestate header value cannot be parsed.
+// Use errors.As to retrieve it and errors.Is to match the wrapped sentinel error.
+type ParseError struct {
+	// Input is the header value that failed to parse.
+	Input string
+	// Field is the failing part of the header, one of the Field constants.
+	Field string
+	// Err is the underlying error.
+	Err error
 }
 
-func Unmarshal(traceparent, tracestate string) (trace.SpanContextConfig, error) {
-	var version, traceID, parentID, flags string
+// Error returns the underlying error prefixed with the failing field.
+// Input is left out so that large header values do not end up in logs;
+// the underlying error quotes at most a truncated prefix of it.
+func (e *ParseError) Error() string {
+	return "tracecontext: parsing " + e.Field + ": " + e.Err.Error()
+}
 
-	if n, err := fmt.Sscanf(traceparent, "%2s-%32s-%16s-%2s", &version, &traceID, &parentID, &flags); err != nil {
-		return trace.SpanContextConfig{}, fmt.Errorf("failed to parse traceparent: %w", err)
-	} else if n != traceparentParts {
-		return trace.SpanContextConfig{}, fmt.Errorf("%w: %s", errTraceparentInvalidFormat, traceparent)
-	}
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
 
-	if version != traceparentVersion {
-		return trace.SpanContextConfig{}, fmt.Errorf("%w: %s", errTraceparentInvalidVersion, version)
+// newParseError returns a ParseError for err, or nil if err is nil.
+func newParseError(input, field string, err error) error {
+	if err == nil {
+		return nil
 	}
 
-	var cfgTraceID, cfgSpanID, cgfTraceFlags []byte
+	return &ParseError{Input: input, Field: field, Err: err}
+}
 
-	var cfgTraceState trace.TraceState
+func Marshal(sc trace.SpanContext) string {
+	traceID, spanID, flags := sc.TraceID(), sc.SpanID(), sc.TraceFlags()
+
+	var buf [traceparentLength]byte
 
-	var err error
+	copy(buf[:], traceparentVersion)
+	buf[traceIDOffset-1] = '-'
+	hex.Encode(buf[traceIDOffset:], traceID[:])
+	buf[spanIDOffset-1] = '-'
+	hex.Encode(buf[spanIDOffset:], spanID[:])
+	buf[flagsOffset-1] = '-'
+	hex.Encode(buf[flagsOffset:], []byte{byte(flags)})
 
-	if cfgTraceID, err = hex.DecodeString(traceID); err != nil {
-		return trace.SpanContextConfig{}, fmt.Errorf("failed to decode trace ID: %w", err)
+	return string(buf[:])
+}
+
+// Unmarshal parses the traceparent and tracestate header values into a remote span context config.
+// Errors are returned as *ParseError.
+// A traceparent holding several comma-joined values is rejected with ErrMultipleTraceparent,
+// in which case callers should start a new trace.
+// An empty tracestate, as when the header is absent, yields an empty trace.TraceState.
+// For versions higher than 00, additional fields are ignored and only the sampled flag is kept.
+// Optional whitespace around the traceparent is ignored; whitespace inside it is invalid.
+// In the tracestate, optional whitespace around each member and around its '=' is ignored.
+// Tracestate values longer than 8192 bytes or that cannot be parsed are rejected with ErrInvalidTracestate.
+// Traceparent values longer than 512 bytes are rejected before they are parsed.
+func Unmarshal(traceparent, tracestate string) (trace.SpanContextConfig, error) {
+	version, traceID, parentID, flags, err := SplitSegments(traceparent)
+	if err != nil {
+		return trace.SpanContextConfig{}, newParseError(traceparent, FieldTraceparent, err)
 	}
 
-	if cfgSpanID, err = hex.DecodeString(parentID); err != nil {
-		return trace.SpanContextConfig{}, fmt.Errorf("failed to decode parent ID: %w", err)
+	if err = checkVersion(version); err != nil {
+		return trace.SpanContextConfig{}, newParseError(traceparent, FieldVersion, err)
 	}
 
-	if cgfTraceFlags, err = hex.DecodeString(flags); err != nil {
-		return trace.SpanContextConfig{}, fmt.Errorf("failed to decode flags: %w", err)
+	cfgTraceID, err := decodeTraceID(traceID)
+	if err != nil {
+		return trace.SpanContextConfig{}, newParseError(traceparent, FieldTraceID, err)
 	}
 
-	if cfgTraceState, err = trace.ParseTraceState(tracestate); err != nil {
-		return trace.SpanContextConfig{}, fmt.Errorf("failed to parse tracestate: %w", err)
+	cfgSpanID, err := decodeSpanID(parentID)
+	if err != nil {
+		return trace.SpanContextConfig{}, newParseError(traceparent, FieldParentID, err)
+	}
+
+	cfgTraceFlags, err := decodeFlags(flags)
+	if err != nil {
+		return trace.SpanContextConfig{}, newParseError(traceparent, FieldTraceFlags, err)
+	}
+
+	// Only the sampled flag is defined for versions higher than 00.
+	if version != traceparentVersion {
+		cfgTraceFlags &= trace.FlagsSampled
+	}
+
+	var cfgTraceState trace.TraceState
+
+	if tracestate != "" {
+		if cfgTraceState, err = parseTracestate(tracestate); err != nil {
+			return trace.SpanContextConfig{}, newParseError(tracestate, FieldTracestate, err)
+		}
 	}
 
 	return trace.SpanContextConfig{
-		TraceID:    trace.TraceID(cfgTraceID),
-		SpanID:     trace.SpanID(cfgSpanID),
-		TraceFlags: trace.TraceFlags(cgfTraceFlags[0]),
+		TraceID:    cfgTraceID,
+		SpanID:     cfgSpanID,
+		TraceFlags: cfgTraceFlags,
 		TraceState: cfgTraceState,
 		Remote:     true,
 	}, nil
 }
+
+// UnmarshalLocal is like Unmarshal but returns a span context config that is not marked as remote,
+// for span contexts that were serialized by the local process.
+func UnmarshalLocal(traceparent, tracestate string) (trace.SpanContextConfig, error) {
+	cfg, err := Unmarshal(traceparent, tracestate)
+	if err != nil {
+		return trace.SpanContextConfig{}, err
+	}
+
+	cfg.Remote = false
+
+	return cfg, nil
+}
+
+// Validate checks the traceparent header value and reports every failing field at once,
+// joined with errors.Join. Fields are only checked if the header layout itself is valid.
+// Each error is a *ParseError.
+func Validate(traceparent string) error {
+	version, traceID, parentID, flags, err := SplitSegments(traceparent)
+	if err != nil {
+		return newParseError(traceparent, FieldTraceparent, err)
+	}
+
+	_, traceIDErr := decodeTraceID(traceID)
+	_, spanIDErr := decodeSpanID(parentID)
+	_, flagsErr := decodeFlags(flags)
+
+	return errors.Join(
+		newParseError(traceparent, FieldVersion, checkVersion(version)),
+		newParseError(traceparent, FieldTraceID, traceIDErr),
+		newParseError(traceparent, FieldParentID, spanIDErr),
+		newParseError(traceparent, FieldTraceFlags, flagsErr),
+	)
+}
+
+// Canonicalize returns the canonical version 00 form of a traceparent header value,
+// suitable as a cache or deduplication key. Surrounding whitespace is dropped and
+// higher versions are rewritten as version 00 without their additional fields.
+func Canonicalize(traceparent string) (string, error) {
+	cfg, err := Unmarshal(traceparent, "")
+	if err != nil {
+		return "", err
+	}
+
+	return Marshal(trace.NewSpanContext(cfg)), nil
+}
+
+// SpanContextEqual reports whether a and b have the same trace ID, span ID, flags, remote flag and
+// tracestate members. Unlike trace.SpanContext.Equal, the tracestate member order is ignored.
+func SpanContextEqual(a, b trace.SpanContext) bool {
+	if a.TraceID() != b.TraceID() || a.SpanID() != b.SpanID() ||
+		a.TraceFlags() != b.TraceFlags() || a.IsRemote() != b.IsRemote() {
+		return false
+	}
+
+	tsA, tsB := a.TraceState(), b.TraceState()
+	if tsA.Len() != tsB.Len() {
+		return false
+	}
+
+	equal := true
+
+	tsA.Walk(func(key, value string) bool {
+		equal = tsB.Get(key) == value
+
+		return equal
+	})
+
+	return equal
+}
+
+// PadTraceID left-pads a 64-bit (16 hex characters) trace ID with zeros to 128 bits (32 hex characters).
+// 128-bit trace IDs are returned unchanged. Input that is not 16 or 32 lowercase hex characters is
+// rejected with ErrInvalidFormat, and an all-zero trace ID with ErrInvalidTraceID.
+func PadTraceID(hexID string) (string, error) {
+	var id trace.TraceID
+
+	switch {
+	case decodeHex(id[:], hexID):
+	case decodeHex(id[len(id)/2:], hexID):
+		hexID = strings.Repeat("0", len(hexID)) + hexID
+	default:
+		return "", fmt.Errorf("failed to decode trace ID: %w: %q", ErrInvalidFormat, truncate(hexID))
+	}
+
+	if !id.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTraceID, hexID)
+	}
+
+	return hexID, nil
+}
+
+// SplitSegments splits a traceparent header value into its version, trace ID, parent ID and flags fields.
+// Only the field count and the hyphen positions are checked; version 00 must have exactly four fields,
+// while higher versions may carry additional ones, which are dropped. Field values are not validated.
+// Optional whitespace around the value is ignored; whitespace inside it is invalid.
+// Values longer than 512 bytes are rejected with ErrInvalidFormat without being scanned.
+func SplitSegments(traceparent string) (version, traceID, parentID, flags string, err error) {
+	if len(traceparent) > traceparentMaxLength {
+		return "", "", "", "", fmt.Errorf("%w: length %d exceeds %d", ErrInvalidFormat, len(traceparent), traceparentMaxLength)
+	}
+
+	traceparent = trimOWS(traceparent)
+
+	if err = checkFormat(traceparent); err != nil {
+		return "", "", "", "", err
+	}
+
+	return traceparent[:traceIDOffset-1],
+		traceparent[traceIDOffset : spanIDOffset-1],
+		traceparent[spanIDOffset : flagsOffset-1],
+		traceparent[flagsOffset:traceparentLength],
+		nil
+}
+
+// checkFormat checks that the traceparent is a single value with hyphens at the field boundaries.
+func checkFormat(traceparent string) error {
+	if strings.IndexByte(traceparent, ',') >= 0 {
+		return fmt.Errorf("%w: %q", ErrMultipleTraceparent, truncate(traceparent))
+	}
+
+	if len(traceparent) < traceparentLength {
+		return fmt.Errorf("%w: %q", ErrInvalidFormat, traceparent)
+	}
+
+	for _, pos := range [...]int{traceIDOffset - 1, spanIDOffset - 1, flagsOffset - 1} {
+		if traceparent[pos] != '-' {
+			return fmt.Errorf("%w: expected '-' at position %d: %q", ErrInvalidFormat, pos, truncate(traceparent))
+		}
+	}
+
+	// Higher versions may append fields after the flags, separated by a hyphen.
+	if len(traceparent) > traceparentLength &&
+		(traceparent[:traceIDOffset-1] == traceparentVersion || traceparent[traceparentLength] != '-') {
+		return fmt.Errorf("%w: %q", ErrInvalidFormat, truncate(traceparent))
+	}
+
+	return nil
+}
+
+// parseTracestate parses a tracestate header value of at most 8192 bytes.
+// Errors quote a truncated prefix of the value rather than the trace.ParseTraceState error,
+// which holds the whole offending member.
+func parseTracestate(tracestate string) (trace.TraceState, error) {
+	if len(tracestate) > tracestateMaxLength {
+		return trace.TraceState{}, fmt.Errorf("%w: length %d exceeds %d", ErrInvalidTracestate, len(tracestate), tracestateMaxLength)
+	}
+
+	ts, err := trace.ParseTraceState(trimTracestateOWS(tracestate))
+	if err != nil {
+		return trace.TraceState{}, fmt.Errorf("%w: %q", ErrInvalidTracestate, truncate(tracestate))
+	}
+
+	return ts, nil
+}
+
+// trimTracestateOWS strips optional whitespace around each tracestate member and around its '='.
+// The tracestate is returned unchanged if it holds no whitespace.
+func trimTracestateOWS(tracestate string) string {
+	if !strings.ContainsAny(tracestate, " \t") {
+		return tracestate
+	}
+
+	var sb strings.Builder
+
+	for i, member := range strings.Split(tracestate, ",") {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		key, value, ok := strings.Cut(trimOWS(member), "=")
+		sb.WriteString(trimOWS(key))
+
+		if ok {
+			sb.WriteByte('=')
+			sb.WriteString(trimOWS(value))
+		}
+	}
+
+	return sb.String()
+}
+
+// truncate shortens str to the length of a version 00 traceparent header for use in error messages.
+// Together with quoting, it keeps large values and control characters out of logs.
+func truncate(str string) string {
+	if len(str) <= traceparentLength {
+		return str
+	}
+
+	return str[:traceparentLength] + "..."
+}
+
+// checkVersion checks that the traceparent version is one that can be parsed.
+func checkVersion(version string) error {
+	if version == traceparentReservedVersion {
+		return fmt.Errorf("%w: %q", ErrVersionReserved, version)
+	}
+
+	var v [1]byte
+	if !decodeHex(v[:], version) {
+		return fmt.Errorf("%w: %q", ErrVersionUnsupported, version)
+	}
+
+	return nil
+}
+
+// decodeTraceID decodes a non-zero trace ID.
+func decodeTraceID(traceID string) (trace.TraceID, error) {
+	var id trace.TraceID
+
+	if !decodeHex(id[:], traceID) {
+		return trace.TraceID{}, fmt.Errorf("failed to decode trace ID: %w: %q", ErrInvalidFormat, traceID)
+	}
+
+	if !id.IsValid() {
+		return trace.TraceID{}, fmt.Errorf("%w: %q", ErrInvalidTraceID, traceID)
+	}
+
+	return id, nil
+}
+
+// decodeSpanID decodes a non-zero parent ID.
+func decodeSpanID(parentID string) (trace.SpanID, error) {
+	var id trace.SpanID
+
+	if !decodeHex(id[:], parentID) {
+		return trace.SpanID{}, fmt.Errorf("failed to decode parent ID: %w: %q", ErrInvalidFormat, parentID)
+	}
+
+	if !id.IsValid() {
+		return trace.SpanID{}, fmt.Errorf("%w: %q", ErrInvalidSpanID, parentID)
+	}
+
+	return id, nil
+}
+
+// decodeFlags decodes the trace flags.
+func decodeFlags(flags string) (trace.TraceFlags, error) {
+	var f [1]byte
+
+	if !decodeHex(f[:], flags) {
+		return 0, fmt.Errorf("failed to decode flags: %w: %q", ErrInvalidFormat, flags)
+	}
+
+	return trace.TraceFlags(f[0]), nil
+}
+
+// decodeHex decodes the lowercase hex string src into dst without allocating.
+// It reports false if src is not exactly len(dst) bytes of lowercase hex.
+func decodeHex(dst []byte, src string) bool {
+	if len(src) != 2*len(dst) {
+		return false
+	}
+
+	for i := range dst {
+		hi, ok := fromHexChar(src[2*i])
+		if !ok {
+			return false
+		}
+
+		lo, ok := fromHexChar(src[2*i+1])
+		if !ok {
+			return false
+		}
+
+		dst[i] = hi<<4 | lo
+	}
+
+	return true
+}
+
+// fromHexChar converts a lowercase hex character into its value.
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	}
+
+	return 0, false
+}
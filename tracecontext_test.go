@@ -0,0 +1,663 @@
+package tracecontext
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+const (
+	// unmarshalMaxAllocs is the allowed number of allocations for parsing a valid traceparent
+	// with an empty tracestate.
+	unmarshalMaxAllocs = 0
+
+	// marshalMaxAllocs is the allowed number of allocations for serializing a span context,
+	// which is the returned string.
+	marshalMaxAllocs = 1
+)
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErr     error
+	}{
+		{"valid", testTraceparent, nil},
+		{"trailing field", testTraceparent + "-xx", ErrInvalidFormat},
+		{"trailing whitespace", testTraceparent + " ", nil},
+		{"all-zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ErrInvalidTraceID},
+		{"single non-zero trace ID nibble", "00-00000000000000000000000000000001-00f067aa0ba902b7-01", nil},
+		{"uppercase trace ID", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ErrInvalidFormat},
+		{"uppercase parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01", ErrInvalidFormat},
+		{"uppercase flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0A", ErrInvalidFormat},
+		{"future version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", nil},
+		{"future version with unseparated extra bytes", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01x", ErrInvalidFormat},
+		{"all-zero parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ErrInvalidSpanID},
+		{"empty flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-", ErrInvalidFormat},
+		{"short flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", ErrInvalidFormat},
+		{"comma-joined values", testTraceparent + "," + testTraceparent, ErrMultipleTraceparent},
+		{"surrounding whitespace", " \t" + testTraceparent + "\t ", nil},
+		{"whitespace inside a field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba90 b7-01", ErrInvalidFormat},
+		{"whitespace around a separator", "00 -4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrInvalidFormat},
+		{"reserved version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrVersionReserved},
+		{"higher version 99", "99-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil},
+		{"non-hex version", "zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrVersionUnsupported},
+		{"uppercase version", "0A-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrVersionUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Unmarshal(tt.traceparent, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal(%q) error = %v, want %v", tt.traceparent, err, tt.wantErr)
+			}
+
+			if err == nil && !trace.NewSpanContext(cfg).IsValid() {
+				t.Fatalf("Unmarshal(%q) returned an invalid span context", tt.traceparent)
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := Unmarshal(testTraceparent, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErrs    []error
+	}{
+		{"valid", testTraceparent, nil},
+		{"zero trace and parent IDs", "00-00000000000000000000000000000000-0000000000000000-01", []error{ErrInvalidTraceID, ErrInvalidSpanID}},
+		{"reserved version and uppercase flags", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0A", []error{ErrVersionReserved, ErrInvalidFormat}},
+		{"bad layout", "00-4bf92f3577b34da6a3ce929d0e0e4736", []error{ErrInvalidFormat}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.traceparent)
+			if (err == nil) != (len(tt.wantErrs) == 0) {
+				t.Fatalf("Validate(%q) error = %v, want %v", tt.traceparent, err, tt.wantErrs)
+			}
+
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Fatalf("Validate(%q) error = %v, want it to match %v", tt.traceparent, err, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalInvalid(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := Unmarshal("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ""); err == nil {
+			b.Fatal("expected an error")
+		}
+	}
+}
+
+func BenchmarkUnmarshalTracestate(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := Unmarshal(testTraceparent, testTracestate); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	cfg, err := Unmarshal(testTraceparent, "")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	sc := trace.NewSpanContext(cfg)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		_ = Marshal(sc)
+	}
+}
+
+func TestUnmarshalAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = Unmarshal(testTraceparent, "")
+	})
+
+	if allocs > unmarshalMaxAllocs {
+		t.Fatalf("Unmarshal allocates %v times, want at most %d", allocs, unmarshalMaxAllocs)
+	}
+}
+
+func TestMarshalAllocs(t *testing.T) {
+	cfg, err := Unmarshal(testTraceparent, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := trace.NewSpanContext(cfg)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = Marshal(sc)
+	})
+
+	if allocs > marshalMaxAllocs {
+		t.Fatalf("Marshal allocates %v times, want at most %d", allocs, marshalMaxAllocs)
+	}
+}
+
+func TestPadTraceID(t *testing.T) {
+	tests := []struct {
+		name    string
+		hexID   string
+		want    string
+		wantErr error
+	}{
+		{"64-bit", "00f067aa0ba902b7", "000000000000000000f067aa0ba902b7", nil},
+		{"128-bit", "4bf92f3577b34da6a3ce929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736", nil},
+		{"invalid length", "00f067aa0ba902b70000", "", ErrInvalidFormat},
+		{"uppercase", "00F067AA0BA902B7", "", ErrInvalidFormat},
+		{"all-zero 64-bit", "0000000000000000", "", ErrInvalidTraceID},
+		{"all-zero 128-bit", "00000000000000000000000000000000", "", ErrInvalidTraceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PadTraceID(tt.hexID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PadTraceID(%q) error = %v, want %v", tt.hexID, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("PadTraceID(%q) = %q, want %q", tt.hexID, got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add(testTraceparent, "")
+	f.Add(testTraceparent, testTracestate)
+	f.Add("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", "")
+	f.Add(testTraceparent[:54], "")
+	f.Add(testTraceparent[:36], "")
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, traceparent, tracestate string) {
+		cfg, err := Unmarshal(traceparent, tracestate)
+		if err != nil {
+			return
+		}
+
+		sc := trace.NewSpanContext(cfg)
+
+		again, err := Unmarshal(Marshal(sc), cfg.TraceState.String())
+		if err != nil {
+			t.Fatalf("Unmarshal(Marshal(%q)) error = %v", traceparent, err)
+		}
+
+		if !SpanContextEqual(sc, trace.NewSpanContext(again)) {
+			t.Fatalf("round trip of %q changed the span context", traceparent)
+		}
+	})
+}
+
+func TestUnmarshalRemote(t *testing.T) {
+	tests := []struct {
+		name       string
+		unmarshal  func(string, string) (trace.SpanContextConfig, error)
+		wantRemote bool
+	}{
+		{"Unmarshal", Unmarshal, true},
+		{"UnmarshalLocal", UnmarshalLocal, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.unmarshal(testTraceparent, "")
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+
+			if cfg.Remote != tt.wantRemote {
+				t.Fatalf("%s() Remote = %v, want %v", tt.name, cfg.Remote, tt.wantRemote)
+			}
+		})
+	}
+}
+
+func TestUnmarshalEmptyTracestate(t *testing.T) {
+	cfg, err := Unmarshal(testTraceparent, "")
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if cfg.TraceState.Len() != 0 {
+		t.Fatalf("TraceState = %q, want empty", cfg.TraceState.String())
+	}
+
+	if !trace.NewSpanContext(cfg).IsValid() {
+		t.Fatal("Unmarshal() returned an invalid span context")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        string
+		wantErr     bool
+	}{
+		{"canonical", testTraceparent, testTraceparent, false},
+		{"surrounding whitespace", " \t" + testTraceparent + " ", testTraceparent, false},
+		{"future version", "05-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", testTraceparent, false},
+		{"future version with unknown flags", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-ff-x", testTraceparent, false},
+		{"future version with only unknown flags", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-fe-x", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false},
+		{"invalid", "garbage", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.traceparent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Canonicalize(%q) error = %v, wantErr %v", tt.traceparent, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Canonicalize(%q) = %q, want %q", tt.traceparent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanContextEqual(t *testing.T) {
+	spanContext := func(traceparent, tracestate string) trace.SpanContext {
+		t.Helper()
+
+		cfg, err := Unmarshal(traceparent, tracestate)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q, %q) error = %v", traceparent, tracestate, err)
+		}
+
+		return trace.NewSpanContext(cfg)
+	}
+
+	a := spanContext(testTraceparent, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE")
+
+	tests := []struct {
+		name string
+		b    trace.SpanContext
+		want bool
+	}{
+		{"identical", spanContext(testTraceparent, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"), true},
+		{"reordered tracestate", spanContext(testTraceparent, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"), true},
+		{"different tracestate value", spanContext(testTraceparent, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b8"), false},
+		{"missing tracestate member", spanContext(testTraceparent, "rojo=00f067aa0ba902b7"), false},
+		{"different flags", spanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"), false},
+		{"different remote flag", a.WithRemote(false), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SpanContextEqual(a, tt.b); got != tt.want {
+				t.Fatalf("SpanContextEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if a.Equal(tests[1].b) {
+		t.Fatal("trace.SpanContext.Equal() unexpectedly ignores tracestate order")
+	}
+}
+
+func TestUnmarshalMisplacedHyphen(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantPos     string
+	}{
+		{"after version", "004-bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "position 2"},
+		{"after trace ID", "00-4bf92f3577b34da6a3ce929d0e0e473-600f067aa0ba902b7-01", "position 35"},
+		{"after parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b-701", "position 52"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.traceparent, "")
+			if !errors.Is(err, ErrInvalidFormat) {
+				t.Fatalf("Unmarshal(%q) error = %v, want %v", tt.traceparent, err, ErrInvalidFormat)
+			}
+
+			if !strings.Contains(err.Error(), tt.wantPos) {
+				t.Fatalf("Unmarshal(%q) error = %v, want it to mention %s", tt.traceparent, err, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestUnmarshalParseError(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		tracestate  string
+		wantField   string
+		wantInput   string
+		wantErr     error
+	}{
+		{"bad layout", "00-4bf92f3577b34da6a3ce929d0e0e4736", "", FieldTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736", ErrInvalidFormat},
+		{"reserved version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", FieldVersion, "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrVersionReserved},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", FieldTraceID, "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ErrInvalidTraceID},
+		{"zero parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "", FieldParentID, "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ErrInvalidSpanID},
+		{"uppercase flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0A", "", FieldTraceFlags, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0A", ErrInvalidFormat},
+		{"invalid tracestate", testTraceparent, "rojo", FieldTracestate, "rojo", ErrInvalidTracestate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.traceparent, tt.tracestate)
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Unmarshal(%q, %q) error = %v, want a *ParseError", tt.traceparent, tt.tracestate, err)
+			}
+
+			if perr.Field != tt.wantField || perr.Input != tt.wantInput {
+				t.Fatalf("ParseError Field = %q, Input = %q, want %q, %q", perr.Field, perr.Input, tt.wantField, tt.wantInput)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal(%q) error = %v, want %v", tt.traceparent, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestW3CConformance runs the traceparent cases of the W3C trace-context test suite
+// (https://github.com/w3c/trace-context/tree/main/test) through Unmarshal and Validate.
+func TestW3CConformance(t *testing.T) {
+	const (
+		traceID  = "12345678901234567890123456789012"
+		parentID = "1234567890123456"
+	)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		valid       bool
+		wantFlags   trace.TraceFlags
+	}{
+		{"sampled", "00-" + traceID + "-" + parentID + "-01", true, trace.FlagsSampled},
+		{"not sampled", "00-" + traceID + "-" + parentID + "-00", true, 0},
+		{"unknown flags", "00-" + traceID + "-" + parentID + "-09", true, 0x09},
+		{"leading whitespace", " \t00-" + traceID + "-" + parentID + "-01", true, trace.FlagsSampled},
+		{"trailing whitespace", "00-" + traceID + "-" + parentID + "-01 \t", true, trace.FlagsSampled},
+		{"future version", "cc-" + traceID + "-" + parentID + "-01", true, trace.FlagsSampled},
+		{"future version with extra field", "cc-" + traceID + "-" + parentID + "-01-what-the-future-will-be-like", true, trace.FlagsSampled},
+		{"future version with unseparated extra field", "cc-" + traceID + "-" + parentID + "-01.what-the-future-will-not-be-like", false, 0},
+		{"version 00 with extra field", "00-" + traceID + "-" + parentID + "-01-what-the-future-will-not-be-like", false, 0},
+		{"version 00 with trailing hyphen", "00-" + traceID + "-" + parentID + "-01-", false, 0},
+		{"multiple values", "00-" + traceID + "-" + parentID + "-01,00-" + traceID + "-" + parentID + "-00", false, 0},
+		{"reserved version", "ff-" + traceID + "-" + parentID + "-01", false, 0},
+		{"short version", "0-" + traceID + "-" + parentID + "-01", false, 0},
+		{"long version", "000-" + traceID + "-" + parentID + "-01", false, 0},
+		{"non-hex version", ".0-" + traceID + "-" + parentID + "-01", false, 0},
+		{"uppercase version", "AA-" + traceID + "-" + parentID + "-01", false, 0},
+		{"all-zero trace ID", "00-00000000000000000000000000000000-" + parentID + "-01", false, 0},
+		{"uppercase trace ID", "00-ABCDEF78901234567890123456789012-" + parentID + "-01", false, 0},
+		{"non-hex trace ID", "00-.2345678901234567890123456789012-" + parentID + "-01", false, 0},
+		{"short trace ID", "00-1234567890123456789012345678901-" + parentID + "-01", false, 0},
+		{"long trace ID", "00-123456789012345678901234567890123-" + parentID + "-01", false, 0},
+		{"all-zero parent ID", "00-" + traceID + "-0000000000000000-01", false, 0},
+		{"uppercase parent ID", "00-" + traceID + "-ABCDEF7890123456-01", false, 0},
+		{"non-hex parent ID", "00-" + traceID + "-.234567890123456-01", false, 0},
+		{"short parent ID", "00-" + traceID + "-123456789012345-01", false, 0},
+		{"long parent ID", "00-" + traceID + "-12345678901234567-01", false, 0},
+		{"non-hex flags", "00-" + traceID + "-" + parentID + "-.0", false, 0},
+		{"uppercase flags", "00-" + traceID + "-" + parentID + "-0A", false, 0},
+		{"short flags", "00-" + traceID + "-" + parentID + "-0", false, 0},
+		{"long flags", "00-" + traceID + "-" + parentID + "-001", false, 0},
+		{"empty", "", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Unmarshal(tt.traceparent, "")
+			if (err == nil) != tt.valid {
+				t.Fatalf("Unmarshal(%q) error = %v, want valid %v", tt.traceparent, err, tt.valid)
+			}
+
+			if err := Validate(tt.traceparent); (err == nil) != tt.valid {
+				t.Fatalf("Validate(%q) error = %v, want valid %v", tt.traceparent, err, tt.valid)
+			}
+
+			if !tt.valid {
+				return
+			}
+
+			if got := cfg.TraceID.String(); got != traceID {
+				t.Fatalf("Unmarshal(%q) trace ID = %s, want %s", tt.traceparent, got, traceID)
+			}
+
+			if got := cfg.SpanID.String(); got != parentID {
+				t.Fatalf("Unmarshal(%q) parent ID = %s, want %s", tt.traceparent, got, parentID)
+			}
+
+			if cfg.TraceFlags != tt.wantFlags {
+				t.Fatalf("Unmarshal(%q) flags = %s, want %s", tt.traceparent, cfg.TraceFlags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestUnmarshalOverlong(t *testing.T) {
+	future := "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-"
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErr     error
+	}{
+		{"future version at the cap", future + strings.Repeat("x", traceparentMaxLength-len(future)), nil},
+		{"future version over the cap", future + strings.Repeat("x", traceparentMaxLength-len(future)+1), ErrInvalidFormat},
+		{"huge future version", future + strings.Repeat("x", 4<<20), ErrInvalidFormat},
+		{"huge version 00", testTraceparent + strings.Repeat("-", 4<<20), ErrInvalidFormat},
+		{"huge whitespace", testTraceparent + strings.Repeat(" ", 4<<20), ErrInvalidFormat},
+		{"long future version with a comma", future + strings.Repeat("x", 400) + ",", ErrMultipleTraceparent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.traceparent, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// The raw input is truncated in error messages.
+			if err != nil && len(err.Error()) > 200 {
+				t.Fatalf("Unmarshal() error message is %d bytes long: %v", len(err.Error()), err)
+			}
+
+			if _, err := Canonicalize(tt.traceparent); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Canonicalize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalOverlong(b *testing.B) {
+	traceparent := "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-" + strings.Repeat("x", 4<<20)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if _, err := Unmarshal(traceparent, ""); err == nil {
+			b.Fatal("expected an error")
+		}
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        [4]string
+		wantErr     error
+	}{
+		{"version 00", testTraceparent, [4]string{"00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, nil},
+		{"surrounding whitespace", " " + testTraceparent + "\t", [4]string{"00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, nil},
+		{"future version extra fields dropped", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-a-b", [4]string{"cc", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, nil},
+		{"field values not validated", "zz-00000000000000000000000000000000-0000000000000000-ZZ", [4]string{"zz", "00000000000000000000000000000000", "0000000000000000", "ZZ"}, nil},
+		{"too few fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", [4]string{}, ErrInvalidFormat},
+		{"version 00 extra field", testTraceparent + "-extra", [4]string{}, ErrInvalidFormat},
+		{"shifted hyphen", "00-4bf92f3577b34da6a3ce929d0e0e473-600f067aa0ba902b7-01", [4]string{}, ErrInvalidFormat},
+		{"missing hyphen", "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", [4]string{}, ErrInvalidFormat},
+		{"comma-joined values", testTraceparent + "," + testTraceparent, [4]string{}, ErrMultipleTraceparent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, traceID, parentID, flags, err := SplitSegments(tt.traceparent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SplitSegments(%q) error = %v, want %v", tt.traceparent, err, tt.wantErr)
+			}
+
+			if got := [4]string{version, traceID, parentID, flags}; got != tt.want {
+				t.Fatalf("SplitSegments(%q) = %q, want %q", tt.traceparent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalFutureVersionFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        trace.TraceFlags
+	}{
+		{"version 00 keeps all flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-ff", 0xff},
+		{"future version keeps the sampled flag", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-ff-x", trace.FlagsSampled},
+		{"future version drops unknown flags", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-fe-x", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Unmarshal(tt.traceparent, "")
+			if err != nil {
+				t.Fatalf("Unmarshal(%q) error = %v", tt.traceparent, err)
+			}
+
+			if cfg.TraceFlags != tt.want {
+				t.Fatalf("Unmarshal(%q) flags = %s, want %s", tt.traceparent, cfg.TraceFlags, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalMatchesSprintf(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+
+	tests := []struct {
+		name string
+		sc   trace.SpanContext
+	}{
+		{"flags 00", trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})},
+		{"flags 01", trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})},
+		{"flags ff", trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: 0xff})},
+		{"zero span context", trace.SpanContext{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// want is the output of the former fmt.Sprintf based implementation.
+			want := fmt.Sprintf("%s-%s-%s-%s",
+				traceparentVersion, tt.sc.TraceID().String(), tt.sc.SpanID().String(), tt.sc.TraceFlags().String())
+
+			if got := Marshal(tt.sc); got != want {
+				t.Fatalf("Marshal() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTracestateWhitespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		tracestate string
+		want       string
+	}{
+		{"around member and equals sign", " key = value ", "key=value"},
+		{"after equals sign", "key= value", "key=value"},
+		{"around several members", "rojo = 00f067aa0ba902b7 ,\tcongo=t61rcWkgMzE\t", testTracestate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Unmarshal(testTraceparent, tt.tracestate)
+			if err != nil {
+				t.Fatalf("Unmarshal(%q) error = %v", tt.tracestate, err)
+			}
+
+			if got := cfg.TraceState.String(); got != tt.want {
+				t.Fatalf("Unmarshal(%q) tracestate = %q, want %q", tt.tracestate, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Unmarshal(testTraceparent, "ke y=value"); err == nil {
+		t.Fatal("Unmarshal() accepted whitespace inside a tracestate key")
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		tracestate  string
+	}{
+		{"huge invalid tracestate", testTraceparent, "rojo=" + strings.Repeat("\x01", 1<<20)},
+		{"invalid tracestate member", testTraceparent, "rojo=" + strings.Repeat("x", 300) + "\n"},
+		{"overlong tracestate", testTraceparent, strings.Repeat("rojo=00f067aa0ba902b7,", 1000)},
+		{"control characters in traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736\n00f067aa0ba902b7-01", ""},
+		{"control characters in a future version", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\r\nx", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.traceparent, tt.tracestate)
+			if err == nil {
+				t.Fatal("Unmarshal() error = nil, want an error")
+			}
+
+			msg := err.Error()
+
+			if len(msg) > 200 {
+				t.Fatalf("Unmarshal() error message is %d bytes long: %.200s", len(msg), msg)
+			}
+
+			if strings.ContainsAny(msg, "\x01\r\n") {
+				t.Fatalf("Unmarshal() error message holds control characters: %q", msg)
+			}
+		})
+	}
+}